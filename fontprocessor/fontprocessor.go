@@ -1,6 +1,8 @@
 package fontprocessor
 
 import (
+	"bytes"
+	"compress/zlib"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -9,30 +11,229 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/andybalholm/brotli"
+	"github.com/spf13/afero"
 	"golang.org/x/image/font/sfnt"
 )
 
+// NameID identifies a name table record's semantic meaning (family,
+// subfamily, designer, license, ...), matching the OpenType 'name' table's
+// nameID field.
+type NameID uint16
+
+// Standard OpenType name table nameIDs, as needed to populate FontMetadata.
+const (
+	NameIDCopyright       NameID = 0
+	NameIDFamily          NameID = 1
+	NameIDSubfamily       NameID = 2
+	NameIDUniqueID        NameID = 3
+	NameIDFull            NameID = 4
+	NameIDVersion         NameID = 5
+	NameIDPostScript      NameID = 6
+	NameIDTrademark       NameID = 7
+	NameIDManufacturer    NameID = 8
+	NameIDDesigner        NameID = 9
+	NameIDDescription     NameID = 10
+	NameIDVendorURL       NameID = 11
+	NameIDDesignerURL     NameID = 12
+	NameIDLicense         NameID = 13
+	NameIDLicenseURL      NameID = 14
+	NameIDPreferredFamily NameID = 16
+	NameIDPreferredSubfam NameID = 17
+)
+
+// LanguageTag identifies which platform/encoding/language a name record was
+// written in, so localized variants (e.g. an English and a Japanese family
+// name) can be kept side by side instead of one overwriting the other.
+type LanguageTag struct {
+	PlatformID uint16
+	EncodingID uint16
+	LanguageID uint16
+}
+
+// MarshalText renders a LanguageTag as "platform-encoding-language" so it
+// can be used as a JSON object key (encoding/json requires map keys to
+// implement encoding.TextMarshaler or be a string/integer type).
+func (t LanguageTag) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d-%d-%d", t.PlatformID, t.EncodingID, t.LanguageID)), nil
+}
+
+// UnmarshalText parses the format MarshalText produces.
+func (t *LanguageTag) UnmarshalText(data []byte) error {
+	parts := strings.Split(string(data), "-")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid LanguageTag %q", data)
+	}
+	platformID, err := strconv.ParseUint(parts[0], 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid LanguageTag %q: %v", data, err)
+	}
+	encodingID, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid LanguageTag %q: %v", data, err)
+	}
+	languageID, err := strconv.ParseUint(parts[2], 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid LanguageTag %q: %v", data, err)
+	}
+	t.PlatformID, t.EncodingID, t.LanguageID = uint16(platformID), uint16(encodingID), uint16(languageID)
+	return nil
+}
+
+// Range is an inclusive Unicode code point range, used to record one of the
+// blocks a font claims to cover via OS/2's ulUnicodeRange1..4 bitfield.
+type Range struct {
+	First, Last rune
+}
+
 type FontMetadata struct {
 	FamilyName    string
 	SubfamilyName string
 	FullName      string
+
+	// Names holds every decodable name-table record, keyed by its nameID
+	// and then by the platform/encoding/language it was written in. The
+	// FamilyName/SubfamilyName/FullName/PostScriptName/... fields above and
+	// below are convenience copies of whichever record looks like the font's
+	// primary (typically English) name; Names preserves every localized
+	// variant alongside them.
+	Names map[NameID]map[LanguageTag]string
+
+	PostScriptName string
+	Version        string
+	Designer       string
+	DesignerURL    string
+	Manufacturer   string
+	License        string
+	LicenseURL     string
+	Copyright      string
+	Trademark      string
+
+	// Weight and Width are OS/2's usWeightClass (100-900) and usWidthClass
+	// (1-9); zero when the font has no OS/2 table.
+	Weight int
+	Width  int
+	// IsItalic and IsMonospace come from OS/2's fsSelection italic bit and
+	// post's isFixedPitch field respectively.
+	IsItalic      bool
+	IsMonospace   bool
+	UnicodeRanges []Range
+	// ItalicAngle is post's italicAngle field, in degrees counter-clockwise
+	// from vertical (negative for fonts that slant to the right).
+	ItalicAngle int
+
+	// faceData holds the standalone font bytes for this face when it was
+	// extracted from a collection (.ttc/.otc). nil for single-face inputs,
+	// in which case ProcessFontFile copies the original file instead.
+	faceData []byte
+
+	// ext overrides the output extension processFace would otherwise use,
+	// needed for collection faces: a .ttc/.otc can mix TrueType- and
+	// CFF-flavored (OTTO) faces, so the collection's own extension isn't
+	// necessarily right for every face extracted from it. Empty for
+	// single-face inputs, which use the extension getFontType derives from
+	// the file's signature.
+	ext string
+
+	// font is the parsed sfnt.Font for this face, kept around so a later
+	// --emit-pdfdef pass can pull its metrics without reparsing. nil when
+	// sfnt parsing failed and metadata was recovered by other means.
+	font *sfnt.Font
+}
+
+// FileOptions configures how ProcessFontFile reads, deduplicates, and
+// writes a single font file.
+type FileOptions struct {
+	// Index is consulted for deduplication and incremental rescans; may be nil.
+	Index *Index
+	// ReadFS and WriteFS back every read and write. Both default to the
+	// real OS filesystem when nil; set WriteFS to a NewZipOutputFs() to
+	// group output into per-family archives.
+	ReadFS, WriteFS afero.Fs
+	// EmitPDFDef, when true, also writes a gofpdf-compatible <FullName>.json
+	// font definition next to every copied TrueType/OpenType face.
+	EmitPDFDef bool
+	// PDFEncoding names the single-byte encoding used to compute the
+	// Cw advance-width table in emitted font definitions. Defaults to
+	// "cp1252" when empty. "cp1252" is the only supported value; with any
+	// other, writePDFDef returns an error and no definition is written.
+	PDFEncoding string
+	// EmitManifest, when true, also maintains a manifest.json in every
+	// output family directory enumerating the full FontMetadata of each
+	// face written there, for downstream font-picker UIs to consume.
+	EmitManifest bool
 }
 
-func ProcessFontFile(inputDir, inputPath, outputDir string, logWriter io.Writer) error {
+// ProcessFontFile processes a single font file according to opts. Zero
+// value FileOptions{} is a valid "do the simplest thing" configuration:
+// the real OS filesystem, no dedup index, no PDF definitions.
+func ProcessFontFile(inputDir, inputPath, outputDir string, logWriter io.Writer, opts FileOptions) error {
+	if opts.ReadFS == nil {
+		opts.ReadFS = afero.NewOsFs()
+	}
+	if opts.WriteFS == nil {
+		opts.WriteFS = afero.NewOsFs()
+	}
+	return processFontFile(inputDir, inputPath, outputDir, logWriter, opts, nil)
+}
+
+func processFontFile(inputDir, inputPath, outputDir string, logWriter io.Writer, opts FileOptions, locker *dirLocker) error {
+	idx := opts.Index
+	readFS := opts.ReadFS
+
+	if idx != nil {
+		if info, err := readFS.Stat(inputPath); err == nil {
+			if entry, ok := idx.LookupUnchanged(inputPath, info.Size(), info.ModTime()); ok {
+				fmt.Fprintf(logWriter, "Skipping %s: unchanged since last scan (indexed as %s)\n", filepath.Base(inputPath), entry.OutputPath)
+				return nil
+			}
+		}
+	}
+
 	// Read font file
-	data, err := os.ReadFile(inputPath)
+	data, err := afero.ReadFile(readFS, inputPath)
 	if err != nil {
 		return fmt.Errorf("failed to read %s: %v", filepath.Base(inputPath), err)
 	}
 
-	// Parse font metadata
-	metadata, ext, err := parseFont(data, inputPath, logWriter)
+	hash := hashBytes(data)
+	if idx != nil {
+		if entry, ok := idx.LookupHash(hash); ok {
+			fmt.Fprintf(logWriter, "Skipping %s: content already indexed as %s\n", filepath.Base(inputPath), entry.OutputPath)
+			return nil
+		}
+	}
+
+	// Parse font metadata (one entry per face; collections yield more than one)
+	faces, ext, err := parseFont(data, inputPath, logWriter)
 	if err != nil {
 		return fmt.Errorf("failed to parse %s: %v", filepath.Base(inputPath), err)
 	}
+	_, fontType, _ := getFontType(data, inputPath)
 
-	// Use filename as fallback
 	filename := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	relPath, err := filepath.Rel(inputDir, filepath.Dir(inputPath))
+	if err != nil {
+		return fmt.Errorf("failed to compute relative path for %s: %v", inputPath, err)
+	}
+
+	for i := range faces {
+		if err := processFace(inputPath, outputDir, relPath, ext, i, &faces[i], filename, hash, fontType, logWriter, locker, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processFace writes a single parsed face to disk, resolving its output
+// path and (when locker is non-nil) holding that output directory's lock
+// for the full create+resolveDuplicate+write sequence.
+func processFace(inputPath, outputDir, relPath, ext string, faceIndex int, metadata *FontMetadata, filename, hash, fontType string, logWriter io.Writer, locker *dirLocker, opts FileOptions) error {
+	idx := opts.Index
+	readFS, writeFS := opts.ReadFS, opts.WriteFS
+	if metadata.ext != "" {
+		ext = metadata.ext
+	}
 	if metadata.FamilyName == "" {
 		metadata.FamilyName = filename
 	}
@@ -50,47 +251,97 @@ func ProcessFontFile(inputDir, inputPath, outputDir string, logWriter io.Writer)
 	safeFullName := sanitizeFileName(metadata.FullName)
 
 	// Determine output path (group by FamilyName)
-	relPath, err := filepath.Rel(inputDir, filepath.Dir(inputPath))
-	if err != nil {
-		return fmt.Errorf("failed to compute relative path for %s: %v", inputPath, err)
-	}
 	outputSubDir := filepath.Join(relPath, safeFamilyName)
 	if relPath == "." {
 		outputSubDir = safeFamilyName
 	}
 	outputPath := filepath.Join(outputDir, outputSubDir, safeFullName+ext)
+	outputDirPath := filepath.Dir(outputPath)
+
+	// The directory-create + resolveDuplicate + write sequence below is a
+	// stat-then-act race if two workers target the same output directory
+	// concurrently, so serialize it per directory.
+	if locker != nil {
+		unlock := locker.lock(outputDirPath)
+		defer unlock()
+	}
 
 	// Create directory
-	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
-		return fmt.Errorf("failed to create directory %s: %v", filepath.Dir(outputPath), err)
+	if err := writeFS.MkdirAll(outputDirPath, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %v", outputDirPath, err)
 	}
 
 	// Resolve duplicates
-	outputPath, isDuplicate, err := resolveDuplicate(outputPath, logWriter)
+	outputPath, isDuplicate, err := resolveDuplicate(writeFS, outputPath, logWriter)
 	if err != nil {
 		return fmt.Errorf("failed to resolve output path for %s: %v", filepath.Base(inputPath), err)
 	}
 	if isDuplicate {
-		return nil // Termina el procesamiento si es un duplicado
+		return nil // Termina el procesamiento de esta cara si es un duplicado
 	}
 
-	// Copy file
-	if err := copyFile(inputPath, outputPath); err != nil {
+	// Copy file (or collection face bytes)
+	if metadata.faceData != nil {
+		if err := afero.WriteFile(writeFS, outputPath, metadata.faceData, 0644); err != nil {
+			return fmt.Errorf("failed to write face %d of %s to %s: %v", faceIndex, filepath.Base(inputPath), outputPath, err)
+		}
+	} else if err := copyFile(readFS, writeFS, inputPath, outputPath); err != nil {
 		return fmt.Errorf("failed to copy %s to %s: %v", filepath.Base(inputPath), outputPath, err)
 	}
 
 	fmt.Fprintf(logWriter, "Copied %s to %s\n", filepath.Base(inputPath), outputPath)
 	fmt.Fprintf(os.Stdout, "Copied %s to %s\n", filepath.Base(inputPath), outputPath)
+
+	if opts.EmitPDFDef {
+		if metadata.font == nil {
+			fmt.Fprintf(logWriter, "Skipping PDF definition for %s: no parsed sfnt.Font available\n", metadata.FullName)
+		} else {
+			originalSize := len(metadata.faceData)
+			if originalSize == 0 {
+				if info, err := readFS.Stat(inputPath); err == nil {
+					originalSize = int(info.Size())
+				}
+			}
+			if err := writePDFDef(writeFS, metadata, outputPath, opts.PDFEncoding, originalSize); err != nil {
+				fmt.Fprintf(logWriter, "Failed to write PDF definition for %s: %v\n", metadata.FullName, err)
+			}
+		}
+	}
+
+	if opts.EmitManifest {
+		entry := ManifestEntry{FontMetadata: *metadata, OutputPath: outputPath}
+		if err := appendManifestEntry(writeFS, outputDirPath, entry); err != nil {
+			fmt.Fprintf(logWriter, "Failed to update manifest for %s: %v\n", outputPath, err)
+		}
+	}
+
+	if idx != nil {
+		entry := IndexEntry{
+			Hash:          hash,
+			InputPath:     inputPath,
+			OutputPath:    outputPath,
+			FamilyName:    metadata.FamilyName,
+			SubfamilyName: metadata.SubfamilyName,
+			FullName:      metadata.FullName,
+			FontType:      fontType,
+			FaceIndex:     faceIndex,
+		}
+		if info, err := readFS.Stat(inputPath); err == nil {
+			entry.Size = info.Size()
+			entry.ModTime = info.ModTime()
+		}
+		idx.Add(entry)
+	}
 	return nil
 }
 
-func parseFont(data []byte, inputPath string, logWriter io.Writer) (FontMetadata, string, error) {
+func parseFont(data []byte, inputPath string, logWriter io.Writer) ([]FontMetadata, string, error) {
 	// Determine font type and extension
 	ext, fontType, err := getFontType(data, inputPath)
 	if err != nil {
 		filename := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
 		fmt.Fprintf(logWriter, "Invalid font signature for %s: %v\n", filepath.Base(inputPath), err)
-		return inferMetadata(filename, fontType), ext, nil
+		return []FontMetadata{inferMetadata(filename, fontType)}, ext, nil
 	}
 
 	switch fontType {
@@ -99,16 +350,42 @@ func parseFont(data []byte, inputPath string, logWriter io.Writer) (FontMetadata
 		if err != nil {
 			filename := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
 			fmt.Fprintf(logWriter, "Failed to parse Type 1 metadata for %s: %v\n", filepath.Base(inputPath), err)
-			return inferMetadata(filename, fontType), ext, nil
+			return []FontMetadata{inferMetadata(filename, fontType)}, ext, nil
 		}
-		return metadata, ext, nil
+		return []FontMetadata{metadata}, ext, nil
+	case "collection":
+		faces, err := parseCollection(data, logWriter)
+		if err != nil || len(faces) == 0 {
+			filename := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+			fmt.Fprintf(logWriter, "Failed to parse font collection %s: %v\n", filepath.Base(inputPath), err)
+			return []FontMetadata{inferMetadata(filename, fontType)}, ".ttf", nil
+		}
+		return faces, ".ttf", nil
+	case "woff":
+		metadata, err := parseWOFF(data, logWriter)
+		if err != nil {
+			filename := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+			fmt.Fprintf(logWriter, "Failed to parse WOFF metadata for %s: %v\n", filepath.Base(inputPath), err)
+			return []FontMetadata{inferMetadata(filename, fontType)}, ext, nil
+		}
+		return []FontMetadata{metadata}, ext, nil
+	case "woff2":
+		metadata, err := parseWOFF2(data, logWriter)
+		if err != nil {
+			filename := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+			fmt.Fprintf(logWriter, "Failed to parse WOFF2 metadata for %s: %v\n", filepath.Base(inputPath), err)
+			return []FontMetadata{inferMetadata(filename, fontType)}, ext, nil
+		}
+		return []FontMetadata{metadata}, ext, nil
 	case "truetype", "opentype":
 		// Try sfnt parsing
 		f, err := sfnt.Parse(data)
 		if err == nil {
 			metadata, err := getFontMetadata(f)
 			if err == nil {
-				return metadata, ext, nil
+				metadata.font = f
+				enrichFromTables(data, &metadata, logWriter)
+				return []FontMetadata{metadata}, ext, nil
 			}
 			fmt.Fprintf(logWriter, "Failed to extract metadata for %s: %v\n", filepath.Base(inputPath), err)
 		} else {
@@ -118,18 +395,384 @@ func parseFont(data []byte, inputPath string, logWriter io.Writer) (FontMetadata
 		// Try manual name table parsing
 		metadata, err := parseNameTable(data, logWriter)
 		if err == nil && metadata.FamilyName != "" {
-			return metadata, ext, nil
+			enrichFromTables(data, &metadata, logWriter)
+			return []FontMetadata{metadata}, ext, nil
 		}
 		fmt.Fprintf(logWriter, "Failed to parse name table for %s: %v\n", filepath.Base(inputPath), err)
 
 		// Fallback to inferred metadata
 		filename := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
-		return inferMetadata(filename, fontType), ext, nil
+		return []FontMetadata{inferMetadata(filename, fontType)}, ext, nil
 	default:
 		filename := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
 		fmt.Fprintf(logWriter, "Unknown font type for %s\n", filepath.Base(inputPath))
-		return inferMetadata(filename, fontType), ext, nil
+		return []FontMetadata{inferMetadata(filename, fontType)}, ext, nil
+	}
+}
+
+// parseCollection splits a TrueType/OpenType collection (ttcf) into its
+// individual faces, parsing each one via sfnt.ParseCollection so every face
+// gets its own FamilyName/SubfamilyName/FullName and can be written out as a
+// standalone font file.
+func parseCollection(data []byte, logWriter io.Writer) ([]FontMetadata, error) {
+	coll, err := sfnt.ParseCollection(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse collection header: %v", err)
+	}
+
+	if len(data) < 16 {
+		return nil, fmt.Errorf("collection data too short")
+	}
+	numFonts := binary.BigEndian.Uint32(data[8:12])
+	if len(data) < int(12+numFonts*4) {
+		return nil, fmt.Errorf("invalid collection header")
+	}
+	offsets := make([]uint32, numFonts)
+	for i := range offsets {
+		offsets[i] = binary.BigEndian.Uint32(data[12+i*4 : 16+i*4])
+	}
+
+	faces := make([]FontMetadata, 0, coll.NumFonts())
+	for i := 0; i < coll.NumFonts(); i++ {
+		f, err := coll.Font(i)
+		if err != nil {
+			fmt.Fprintf(logWriter, "Failed to parse face %d of collection: %v\n", i, err)
+			continue
+		}
+		metadata, err := getFontMetadata(f)
+		if err != nil {
+			metadata, err = parseNameTable(faceBytesAt(data, offsets, i), logWriter)
+			if err != nil {
+				fmt.Fprintf(logWriter, "Failed to extract metadata for face %d of collection: %v\n", i, err)
+				continue
+			}
+		}
+		metadata.faceData = extractFace(data, offsets, i)
+		metadata.ext = faceExt(data, offsets, i)
+		metadata.font = f
+		enrichFromTables(metadata.faceData, &metadata, logWriter)
+		faces = append(faces, metadata)
+	}
+	if len(faces) == 0 {
+		return nil, fmt.Errorf("no faces recovered from collection")
+	}
+	return faces, nil
+}
+
+// faceBytesAt returns face i as a standalone font rebuilt by extractFace,
+// used as a best-effort fallback for parseNameTable when sfnt can't resolve
+// the face's metadata directly. Table offsets in a collection count from the
+// start of the collection, so the face is rebuilt rather than sliced out.
+func faceBytesAt(data []byte, offsets []uint32, i int) []byte {
+	return extractFace(data, offsets, i)
+}
+
+// faceExt reports the output extension for face i of a collection by
+// reading its own 4-byte sfnt version tag: CFF-flavored (OTTO) faces are
+// ".otf", everything else (glyf-flavored TrueType) is ".ttf".
+func faceExt(data []byte, offsets []uint32, i int) string {
+	if int(offsets[i])+4 > len(data) {
+		return ".ttf"
 	}
+	if string(data[offsets[i]:offsets[i]+4]) == "OTTO" {
+		return ".otf"
+	}
+	return ".ttf"
+}
+
+// extractFace rebuilds a standalone sfnt file for face i of a collection by
+// copying its table directory and every table it points to, so each face of
+// a .ttc/.otc can be written out as an independent .ttf/.otf.
+func extractFace(data []byte, offsets []uint32, i int) []byte {
+	offset := offsets[i]
+	if int(offset)+12 > len(data) {
+		return nil
+	}
+	numTables := binary.BigEndian.Uint16(data[offset+4 : offset+6])
+	dirSize := 12 + int(numTables)*16
+	if int(offset)+dirSize > len(data) {
+		return nil
+	}
+
+	type tableEntry struct {
+		tag            [4]byte
+		checksum       uint32
+		srcOff, length uint32
+	}
+	entries := make([]tableEntry, numTables)
+	for t := 0; t < int(numTables); t++ {
+		rec := data[int(offset)+12+t*16:]
+		var e tableEntry
+		copy(e.tag[:], rec[0:4])
+		e.checksum = binary.BigEndian.Uint32(rec[4:8])
+		e.srcOff = binary.BigEndian.Uint32(rec[8:12])
+		e.length = binary.BigEndian.Uint32(rec[12:16])
+		entries[t] = e
+	}
+
+	out := make([]byte, dirSize)
+	copy(out, data[offset:int(offset)+dirSize])
+	headOffset := -1
+	for t, e := range entries {
+		newOffset := uint32(len(out))
+		if int(e.srcOff+e.length) > len(data) {
+			continue
+		}
+		out = append(out, data[e.srcOff:e.srcOff+e.length]...)
+		// pad to a 4-byte boundary, as sfnt tables require
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+		rec := out[12+t*16 : 12+t*16+16]
+		binary.BigEndian.PutUint32(rec[8:12], newOffset)
+		_ = e.checksum
+		if e.tag == [4]byte{'h', 'e', 'a', 'd'} {
+			headOffset = int(newOffset)
+		}
+	}
+	if headOffset >= 0 {
+		fixHeadChecksumAdjustment(out, headOffset)
+	}
+	return out
+}
+
+// fixHeadChecksumAdjustment recomputes the head table's checksumAdjustment
+// (the field at headOffset+8) now that extractFace has rebuilt the file with
+// new table offsets: checksumAdjustment is a checksum over the whole font,
+// so copying it verbatim from the source collection leaves it wrong for the
+// extracted standalone file, which some font installers reject outright.
+func fixHeadChecksumAdjustment(out []byte, headOffset int) {
+	binary.BigEndian.PutUint32(out[headOffset+8:headOffset+12], 0)
+
+	var sum uint32
+	for i := 0; i < len(out); i += 4 {
+		var word [4]byte
+		copy(word[:], out[i:min(i+4, len(out))])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	binary.BigEndian.PutUint32(out[headOffset+8:headOffset+12], 0xB1B0AFBA-sum)
+}
+
+// decodeWOFFTable locates tag in a WOFF table directory and zlib-inflates
+// it (WOFF stores each table independently compressed, falling back to raw
+// bytes when compLength == origLength).
+func decodeWOFFTable(data []byte, tag string) ([]byte, error) {
+	const headerSize = 44
+	if len(data) < headerSize {
+		return nil, fmt.Errorf("WOFF header too short")
+	}
+	numTables := binary.BigEndian.Uint16(data[12:14])
+	const entrySize = 20
+	if len(data) < headerSize+int(numTables)*entrySize {
+		return nil, fmt.Errorf("invalid WOFF table directory")
+	}
+
+	for i := 0; i < int(numTables); i++ {
+		entry := data[headerSize+i*entrySize:]
+		if string(entry[0:4]) != tag {
+			continue
+		}
+		offset := binary.BigEndian.Uint32(entry[4:8])
+		compLength := binary.BigEndian.Uint32(entry[8:12])
+		origLength := binary.BigEndian.Uint32(entry[12:16])
+		if int(offset+compLength) > len(data) {
+			return nil, fmt.Errorf("invalid %q table offset", tag)
+		}
+		raw := data[offset : offset+compLength]
+
+		if compLength == origLength {
+			return raw, nil
+		}
+		r, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zlib stream for %q table: %v", tag, err)
+		}
+		defer r.Close()
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, r, int64(origLength)); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to inflate %q table: %v", tag, err)
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("no %q table in WOFF file", tag)
+}
+
+// parseWOFF reads a WOFF header and table directory and builds FontMetadata
+// from its `name` table, additionally enriching it from OS/2 and post when
+// those tables are present.
+func parseWOFF(data []byte, logWriter io.Writer) (FontMetadata, error) {
+	nameTable, err := decodeWOFFTable(data, "name")
+	if err != nil {
+		return FontMetadata{}, err
+	}
+	metadata, err := parseNameTableBytes(nameTable, logWriter)
+	if err != nil {
+		return metadata, err
+	}
+
+	if os2, err := decodeWOFFTable(data, "OS/2"); err == nil {
+		if weight, width, isItalic, ranges, err := parseOS2Bytes(os2); err == nil {
+			metadata.Weight = weight
+			metadata.Width = width
+			metadata.IsItalic = isItalic
+			metadata.UnicodeRanges = ranges
+		}
+	}
+	if post, err := decodeWOFFTable(data, "post"); err == nil {
+		if italicAngle, isMonospace, err := parsePostBytes(post); err == nil {
+			metadata.ItalicAngle = italicAngle
+			metadata.IsMonospace = isMonospace
+		}
+	}
+	return metadata, nil
+}
+
+// woff2KnownTags is the fixed list of 63 table tags WOFF2 can reference by
+// index instead of spelling out, per the WOFF2 spec's known-tags table.
+var woff2KnownTags = []string{
+	"cmap", "head", "hhea", "hmtx", "maxp", "name", "OS/2", "post",
+	"cvt ", "fpgm", "glyf", "loca", "prep", "CFF ", "VORG", "EBDT",
+	"EBLC", "gasp", "hdmx", "kern", "LTSH", "PCLT", "VDMX", "vhea",
+	"vmtx", "BASE", "GDEF", "GPOS", "GSUB", "EBSC", "JSTF", "MATH",
+	"CBDT", "CBLC", "COLR", "CPAL", "SVG ", "sbix", "acnt", "avar",
+	"bdat", "bloc", "bsln", "cvar", "fdsc", "feat", "fmtx", "fvar",
+	"gvar", "hsty", "just", "lcar", "mort", "morx", "opbd", "prop",
+	"trak", "Zapf", "Silf", "Glat", "Gloc", "Feat", "Sill",
+}
+
+// readUintBase128 reads a WOFF2 UIntBase128 value (big-endian base-128, high
+// bit set on all but the last byte).
+func readUintBase128(data []byte, pos int) (uint32, int, error) {
+	var value uint32
+	for i := 0; i < 5; i++ {
+		if pos >= len(data) {
+			return 0, pos, fmt.Errorf("truncated UIntBase128")
+		}
+		b := data[pos]
+		pos++
+		if i == 0 && b == 0x80 {
+			return 0, pos, fmt.Errorf("UIntBase128 has a leading zero byte")
+		}
+		if value&0xFE000000 != 0 {
+			return 0, pos, fmt.Errorf("UIntBase128 overflow")
+		}
+		value = value<<7 | uint32(b&0x7F)
+		if b&0x80 == 0 {
+			return value, pos, nil
+		}
+	}
+	return 0, pos, fmt.Errorf("UIntBase128 too long")
+}
+
+// parseWOFF2 decodes just enough of a WOFF2 container to recover the `name`,
+// `OS/2`, and `post` tables: it Brotli-decompresses the single shared table
+// stream and walks the variable-length table directory to find each table's
+// slice within it, accounting for glyf/loca's transformLength so later
+// tables in the directory aren't misaligned. Reconstructing transformed
+// glyf/loca data itself is out of scope since only metadata is needed here.
+func parseWOFF2(data []byte, logWriter io.Writer) (FontMetadata, error) {
+	var metadata FontMetadata
+	const headerSize = 48
+	if len(data) < headerSize {
+		return metadata, fmt.Errorf("WOFF2 header too short")
+	}
+	numTables := int(binary.BigEndian.Uint16(data[12:14]))
+	totalCompressedSize := binary.BigEndian.Uint32(data[20:24])
+
+	pos := headerSize
+	type woff2Table struct {
+		tag string
+		// streamLength is how many bytes this table actually occupies in the
+		// shared decompressed stream: origLength, except for a transformed
+		// glyf/loca table, which stores its (shorter) transformLength there
+		// instead and reconstructs origLength bytes only when rebuilding the
+		// glyph data, which this decoder doesn't do.
+		streamLength uint32
+	}
+	tables := make([]woff2Table, 0, numTables)
+	for i := 0; i < numTables; i++ {
+		if pos >= len(data) {
+			return metadata, fmt.Errorf("truncated WOFF2 table directory")
+		}
+		flags := data[pos]
+		pos++
+		tagIndex := flags & 0x3F
+		var tag string
+		if tagIndex == 63 {
+			if pos+4 > len(data) {
+				return metadata, fmt.Errorf("truncated WOFF2 arbitrary tag")
+			}
+			tag = string(data[pos : pos+4])
+			pos += 4
+		} else if int(tagIndex) < len(woff2KnownTags) {
+			tag = woff2KnownTags[tagIndex]
+		} else {
+			return metadata, fmt.Errorf("invalid WOFF2 known-tag index %d", tagIndex)
+		}
+
+		origLength, newPos, err := readUintBase128(data, pos)
+		if err != nil {
+			return metadata, fmt.Errorf("failed to read origLength for %q: %v", tag, err)
+		}
+		pos = newPos
+
+		streamLength := origLength
+		transformVersion := (flags >> 6) & 0x3
+		hasTransform := (tag == "glyf" || tag == "loca") && transformVersion != 3
+		if hasTransform {
+			transformLength, newPos, err := readUintBase128(data, pos)
+			if err != nil {
+				return metadata, fmt.Errorf("failed to read transformLength for %q: %v", tag, err)
+			}
+			pos = newPos
+			streamLength = transformLength
+		}
+		tables = append(tables, woff2Table{tag: tag, streamLength: streamLength})
+	}
+
+	if pos+int(totalCompressedSize) > len(data) {
+		return metadata, fmt.Errorf("invalid WOFF2 compressed stream bounds")
+	}
+	decompressed, err := io.ReadAll(brotli.NewReader(bytes.NewReader(data[pos : pos+int(totalCompressedSize)])))
+	if err != nil {
+		return metadata, fmt.Errorf("failed to brotli-decompress table stream: %v", err)
+	}
+
+	tableBytes := make(map[string][]byte, len(tables))
+	streamOffset := 0
+	for _, t := range tables {
+		end := streamOffset + int(t.streamLength)
+		if end > len(decompressed) {
+			return metadata, fmt.Errorf("%s table extends past decompressed stream", t.tag)
+		}
+		tableBytes[t.tag] = decompressed[streamOffset:end]
+		streamOffset = end
+	}
+
+	nameTable, ok := tableBytes["name"]
+	if !ok {
+		return metadata, fmt.Errorf("no name table in WOFF2 file")
+	}
+	metadata, err = parseNameTableBytes(nameTable, logWriter)
+	if err != nil {
+		return metadata, err
+	}
+
+	if os2, ok := tableBytes["OS/2"]; ok {
+		if weight, width, isItalic, ranges, err := parseOS2Bytes(os2); err == nil {
+			metadata.Weight = weight
+			metadata.Width = width
+			metadata.IsItalic = isItalic
+			metadata.UnicodeRanges = ranges
+		}
+	}
+	if post, ok := tableBytes["post"]; ok {
+		if italicAngle, isMonospace, err := parsePostBytes(post); err == nil {
+			metadata.ItalicAngle = italicAngle
+			metadata.IsMonospace = isMonospace
+		}
+	}
+	return metadata, nil
 }
 
 func getFontType(data []byte, inputPath string) (string, string, error) {
@@ -142,6 +785,12 @@ func getFontType(data []byte, inputPath string) (string, string, error) {
 		return ".otf", "opentype", nil
 	case "\x00\x01\x00\x00", "true":
 		return ".ttf", "truetype", nil
+	case "ttcf":
+		return ".ttc", "collection", nil
+	case "wOFF":
+		return ".woff", "woff", nil
+	case "wOF2":
+		return ".woff2", "woff2", nil
 	default:
 		if len(data) >= 2 && data[0] == '%' && data[1] == '!' {
 			return ".pfa", "type1", nil
@@ -179,100 +828,173 @@ func parseType1Font(data []byte) (FontMetadata, error) {
 	return metadata, fmt.Errorf("no FontName found")
 }
 
-func parseNameTable(data []byte, logWriter io.Writer) (FontMetadata, error) {
-	var metadata FontMetadata
+// findTableBytes locates tag inside a full sfnt table directory (an
+// `OTTO`/`\x00\x01\x00\x00`/`true` font, or a standalone face rebuilt by
+// extractFace) and returns its slice of data.
+func findTableBytes(data []byte, tag string) ([]byte, error) {
 	if len(data) < 12 {
-		return metadata, fmt.Errorf("data too short for table directory")
+		return nil, fmt.Errorf("data too short for table directory")
 	}
-
 	numTables := binary.BigEndian.Uint16(data[4:6])
 	if len(data) < int(12+numTables*16) {
-		return metadata, fmt.Errorf("invalid table directory")
+		return nil, fmt.Errorf("invalid table directory")
 	}
-
 	for i := 0; i < int(numTables); i++ {
 		offset := 12 + i*16
-		if string(data[offset:offset+4]) == "name" {
-			tableOffset := binary.BigEndian.Uint32(data[offset+8 : offset+12])
-			tableLength := binary.BigEndian.Uint32(data[offset+12 : offset+16])
-			if int(tableOffset+tableLength) > len(data) {
-				return metadata, fmt.Errorf("invalid name table offset")
-			}
+		if string(data[offset:offset+4]) != tag {
+			continue
+		}
+		tableOffset := binary.BigEndian.Uint32(data[offset+8 : offset+12])
+		tableLength := binary.BigEndian.Uint32(data[offset+12 : offset+16])
+		if int(tableOffset+tableLength) > len(data) {
+			return nil, fmt.Errorf("invalid %q table offset", tag)
+		}
+		return data[tableOffset : tableOffset+tableLength], nil
+	}
+	return nil, fmt.Errorf("no %q table", tag)
+}
 
-			nameTable := data[tableOffset:]
-			if len(nameTable) < 6 {
-				return metadata, fmt.Errorf("name table too short")
+func parseNameTable(data []byte, logWriter io.Writer) (FontMetadata, error) {
+	var metadata FontMetadata
+	nameTable, err := findTableBytes(data, "name")
+	if err != nil {
+		return metadata, fmt.Errorf("no metadata found: %v", err)
+	}
+	return parseNameTableBytes(nameTable, logWriter)
+}
+
+// isPreferredLanguage reports whether a name record in this platform/
+// language combination should win when populating FontMetadata's top-level
+// convenience fields (as opposed to only being recorded in Names). It
+// favors Unicode/Windows-US-English records and the Macintosh default
+// (English, language 0), the same records most tooling treats as a font's
+// primary name.
+func isPreferredLanguage(platformID, languageID uint16) bool {
+	switch platformID {
+	case 0:
+		return true
+	case 1:
+		return languageID == 0
+	case 3:
+		return languageID == 0x0409
+	default:
+		return false
+	}
+}
+
+// decodeNameRecord decodes a name table record's raw bytes for the
+// platform/encoding combinations this repo knows how to read: Unicode and
+// Windows platforms (UTF-16BE), and the common case of Macintosh Roman
+// (platform 1, encoding 0), which is ASCII-safe below 0x80. Anything else
+// is returned empty rather than risk misdecoding it.
+func decodeNameRecord(platformID, encodingID uint16, raw []byte) string {
+	if platformID == 0 || platformID == 3 {
+		if len(raw)%2 != 0 {
+			return ""
+		}
+		runes := make([]rune, 0, len(raw)/2)
+		for k := 0; k < len(raw)-1; k += 2 {
+			r := rune(binary.BigEndian.Uint16(raw[k : k+2]))
+			if r != 0 {
+				runes = append(runes, r)
 			}
-			count := binary.BigEndian.Uint16(nameTable[2:4])
-			stringOffset := binary.BigEndian.Uint16(nameTable[4:6])
-
-			for j := 0; j < int(count); j++ {
-				entryOffset := 6 + j*12
-				if len(nameTable) < entryOffset+12 {
-					fmt.Fprintf(logWriter, "Invalid name table entry at index %d\n", j)
-					continue
-				}
-				platformID := binary.BigEndian.Uint16(nameTable[entryOffset : entryOffset+2])
-				nameID := binary.BigEndian.Uint16(nameTable[entryOffset+6 : entryOffset+8])
-				length := binary.BigEndian.Uint16(nameTable[entryOffset+8 : entryOffset+10])
-				offset := binary.BigEndian.Uint16(nameTable[entryOffset+10 : entryOffset+12])
-
-				if platformID == 0 || platformID == 1 || platformID == 3 {
-					nameStart := int(stringOffset) + int(offset)
-					nameEnd := nameStart + int(length)
-					if nameEnd > len(nameTable) {
-						fmt.Fprintf(logWriter, "Invalid name table string offset for nameID %d\n", nameID)
-						continue
-					}
-					nameBytes := nameTable[nameStart:nameEnd]
-					if len(nameBytes) == 0 {
-						continue
-					}
-					var name string
-					if platformID == 3 || platformID == 0 {
-						if len(nameBytes)%2 != 0 {
-							continue
-						}
-						nameRunes := make([]rune, 0, len(nameBytes)/2)
-						for k := 0; k < len(nameBytes)-1; k += 2 {
-							r := rune(binary.BigEndian.Uint16(nameBytes[k : k+2]))
-							if r != 0 {
-								nameRunes = append(nameRunes, r)
-							}
-						}
-						name = string(nameRunes)
-					} else {
-						name = string(nameBytes)
-						name = strings.Map(func(r rune) rune {
-							if r < 32 || (r > 126 && r < 160) {
-								return -1
-							}
-							return r
-						}, name)
-					}
-					if name == "" {
-						continue
-					}
+		}
+		return string(runes)
+	}
+	if platformID == 1 && encodingID == 0 {
+		return strings.Map(func(r rune) rune {
+			if r < 32 || (r > 126 && r < 160) {
+				return -1
+			}
+			return r
+		}, string(raw))
+	}
+	return ""
+}
 
-					switch nameID {
-					case 1:
-						metadata.FamilyName = name
-					case 2:
-						metadata.SubfamilyName = name
-					case 4:
-						metadata.FullName = name
-					case 16: // Preferred Family
-						if metadata.FamilyName == "" {
-							metadata.FamilyName = name
-						}
-					case 17: // Preferred Subfamily
-						if metadata.SubfamilyName == "" {
-							metadata.SubfamilyName = name
-						}
-					}
-				}
+// parseNameTableBytes parses an isolated `name` table (already sliced out of
+// its containing font, e.g. after WOFF decompression) rather than locating
+// one inside a full sfnt table directory. Every decodable record is kept in
+// Names, keyed by its (nameID, platform/encoding/language) tag; the
+// FamilyName/SubfamilyName/FullName/PostScriptName/... convenience fields
+// are filled from whichever record isPreferredLanguage picks for that
+// nameID, so localized variants survive instead of the last one encountered
+// silently overwriting the others.
+func parseNameTableBytes(nameTable []byte, logWriter io.Writer) (FontMetadata, error) {
+	var metadata FontMetadata
+	if len(nameTable) < 6 {
+		return metadata, fmt.Errorf("name table too short")
+	}
+	count := binary.BigEndian.Uint16(nameTable[2:4])
+	stringOffset := binary.BigEndian.Uint16(nameTable[4:6])
+
+	metadata.Names = make(map[NameID]map[LanguageTag]string)
+	preferred := make(map[NameID]bool)
+
+	for j := 0; j < int(count); j++ {
+		entryOffset := 6 + j*12
+		if len(nameTable) < entryOffset+12 {
+			fmt.Fprintf(logWriter, "Invalid name table entry at index %d\n", j)
+			continue
+		}
+		platformID := binary.BigEndian.Uint16(nameTable[entryOffset : entryOffset+2])
+		encodingID := binary.BigEndian.Uint16(nameTable[entryOffset+2 : entryOffset+4])
+		languageID := binary.BigEndian.Uint16(nameTable[entryOffset+4 : entryOffset+6])
+		nameID := NameID(binary.BigEndian.Uint16(nameTable[entryOffset+6 : entryOffset+8]))
+		length := binary.BigEndian.Uint16(nameTable[entryOffset+8 : entryOffset+10])
+		offset := binary.BigEndian.Uint16(nameTable[entryOffset+10 : entryOffset+12])
+
+		nameStart := int(stringOffset) + int(offset)
+		nameEnd := nameStart + int(length)
+		if nameEnd > len(nameTable) {
+			fmt.Fprintf(logWriter, "Invalid name table string offset for nameID %d\n", nameID)
+			continue
+		}
+		name := decodeNameRecord(platformID, encodingID, nameTable[nameStart:nameEnd])
+		if name == "" {
+			continue
+		}
+
+		tag := LanguageTag{PlatformID: platformID, EncodingID: encodingID, LanguageID: languageID}
+		if metadata.Names[nameID] == nil {
+			metadata.Names[nameID] = make(map[LanguageTag]string)
+		}
+		metadata.Names[nameID][tag] = name
+
+		if preferred[nameID] && !isPreferredLanguage(platformID, languageID) {
+			continue
+		}
+		preferred[nameID] = isPreferredLanguage(platformID, languageID)
+
+		switch nameID {
+		case NameIDCopyright:
+			metadata.Copyright = name
+		case NameIDFamily, NameIDPreferredFamily:
+			if nameID == NameIDFamily || metadata.FamilyName == "" {
+				metadata.FamilyName = name
+			}
+		case NameIDSubfamily, NameIDPreferredSubfam:
+			if nameID == NameIDSubfamily || metadata.SubfamilyName == "" {
+				metadata.SubfamilyName = name
 			}
-			break
+		case NameIDFull:
+			metadata.FullName = name
+		case NameIDVersion:
+			metadata.Version = name
+		case NameIDPostScript:
+			metadata.PostScriptName = name
+		case NameIDTrademark:
+			metadata.Trademark = name
+		case NameIDManufacturer:
+			metadata.Manufacturer = name
+		case NameIDDesigner:
+			metadata.Designer = name
+		case NameIDDesignerURL:
+			metadata.DesignerURL = name
+		case NameIDLicense:
+			metadata.License = name
+		case NameIDLicenseURL:
+			metadata.LicenseURL = name
 		}
 	}
 
@@ -282,6 +1004,196 @@ func parseNameTable(data []byte, logWriter io.Writer) (FontMetadata, error) {
 	return metadata, nil
 }
 
+// unicodeRangeBits maps the most commonly populated OS/2 ulUnicodeRange bits
+// (most of bits 0-67, covering alphabetic scripts, CJK, and a handful of
+// symbol blocks) to the Unicode block they claim coverage of, one range per
+// bit: bit 65, which covers both Vertical Forms and CJK Compatibility Forms,
+// is recorded as Vertical Forms only. Bits 12, 53 and 58, and every bit from
+// 68 up, are left unrecognized rather than guessed at.
+var unicodeRangeBits = map[int]Range{
+	0:  {0x0000, 0x007F}, // Basic Latin
+	1:  {0x0080, 0x00FF}, // Latin-1 Supplement
+	2:  {0x0100, 0x017F}, // Latin Extended-A
+	3:  {0x0180, 0x024F}, // Latin Extended-B
+	4:  {0x0250, 0x02AF}, // IPA Extensions
+	5:  {0x02B0, 0x02FF}, // Spacing Modifier Letters
+	6:  {0x0300, 0x036F}, // Combining Diacritical Marks
+	7:  {0x0370, 0x03FF}, // Greek and Coptic
+	8:  {0x2C80, 0x2CFF}, // Coptic
+	9:  {0x0400, 0x04FF}, // Cyrillic
+	10: {0x0530, 0x058F}, // Armenian
+	11: {0x0590, 0x05FF}, // Hebrew
+	13: {0x0600, 0x06FF}, // Arabic
+	14: {0x07C0, 0x07FF}, // NKo
+	15: {0x0900, 0x097F}, // Devanagari
+	16: {0x0980, 0x09FF}, // Bengali
+	17: {0x0A00, 0x0A7F}, // Gurmukhi
+	18: {0x0A80, 0x0AFF}, // Gujarati
+	19: {0x0B00, 0x0B7F}, // Oriya
+	20: {0x0B80, 0x0BFF}, // Tamil
+	21: {0x0C00, 0x0C7F}, // Telugu
+	22: {0x0C80, 0x0CFF}, // Kannada
+	23: {0x0D00, 0x0D7F}, // Malayalam
+	24: {0x0E00, 0x0E7F}, // Thai
+	25: {0x0E80, 0x0EFF}, // Lao
+	26: {0x10A0, 0x10FF}, // Georgian
+	27: {0x1B00, 0x1B7F}, // Balinese
+	28: {0x1100, 0x11FF}, // Hangul Jamo
+	29: {0x1E00, 0x1EFF}, // Latin Extended Additional
+	30: {0x1F00, 0x1FFF}, // Greek Extended
+	31: {0x2000, 0x206F}, // General Punctuation
+	32: {0x2070, 0x209F}, // Superscripts and Subscripts
+	33: {0x20A0, 0x20CF}, // Currency Symbols
+	34: {0x20D0, 0x20FF}, // Combining Diacritical Marks for Symbols
+	35: {0x2100, 0x214F}, // Letterlike Symbols
+	36: {0x2150, 0x218F}, // Number Forms
+	37: {0x2190, 0x21FF}, // Arrows
+	38: {0x2200, 0x22FF}, // Mathematical Operators
+	39: {0x2300, 0x23FF}, // Miscellaneous Technical
+	40: {0x2400, 0x243F}, // Control Pictures
+	41: {0x2440, 0x245F}, // Optical Character Recognition
+	42: {0x2460, 0x24FF}, // Enclosed Alphanumerics
+	43: {0x2500, 0x257F}, // Box Drawing
+	44: {0x2580, 0x259F}, // Block Elements
+	45: {0x25A0, 0x25FF}, // Geometric Shapes
+	46: {0x2600, 0x26FF}, // Miscellaneous Symbols
+	47: {0x2700, 0x27BF}, // Dingbats
+	48: {0x3000, 0x303F}, // CJK Symbols and Punctuation
+	49: {0x3040, 0x309F}, // Hiragana
+	50: {0x30A0, 0x30FF}, // Katakana
+	51: {0x3100, 0x312F}, // Bopomofo
+	52: {0x3130, 0x318F}, // Hangul Compatibility Jamo
+	54: {0x3200, 0x32FF}, // Enclosed CJK Letters and Months
+	55: {0x3300, 0x33FF}, // CJK Compatibility
+	56: {0xAC00, 0xD7A3}, // Hangul Syllables
+	57: {0xD800, 0xDFFF}, // Surrogates
+	59: {0x4E00, 0x9FFF}, // CJK Unified Ideographs
+	60: {0xE000, 0xF8FF}, // Private Use Area
+	61: {0x31C0, 0x31EF}, // CJK Strokes
+	62: {0xFB00, 0xFB4F}, // Alphabetic Presentation Forms
+	63: {0xFB50, 0xFDFF}, // Arabic Presentation Forms-A
+	64: {0xFE20, 0xFE2F}, // Combining Half Marks
+	65: {0xFE10, 0xFE1F}, // Vertical Forms
+	66: {0xFE50, 0xFE6F}, // Small Form Variants
+	67: {0xFE70, 0xFEFF}, // Arabic Presentation Forms-B
+}
+
+// unicodeRangesFromOS2 decodes OS/2's ulUnicodeRange1..4 bitfield into the
+// Unicode blocks this font claims to cover, per unicodeRangeBits.
+func unicodeRangesFromOS2(ur1, ur2, ur3, ur4 uint32) []Range {
+	words := [4]uint32{ur1, ur2, ur3, ur4}
+	var ranges []Range
+	for bit := 0; bit < 128; bit++ {
+		word, shift := bit/32, uint(bit%32)
+		if words[word]&(1<<shift) == 0 {
+			continue
+		}
+		if r, ok := unicodeRangeBits[bit]; ok {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// parseOS2Bytes reads the OS/2-derived fields FontMetadata tracks. It
+// requires the first 64 bytes of the table (through fsSelection), which
+// every OS/2 version includes.
+func parseOS2Bytes(os2 []byte) (weight, width int, isItalic bool, ranges []Range, err error) {
+	if len(os2) < 64 {
+		return 0, 0, false, nil, fmt.Errorf("OS/2 table too short")
+	}
+	weight = int(binary.BigEndian.Uint16(os2[4:6]))
+	width = int(binary.BigEndian.Uint16(os2[6:8]))
+	ur1 := binary.BigEndian.Uint32(os2[42:46])
+	ur2 := binary.BigEndian.Uint32(os2[46:50])
+	ur3 := binary.BigEndian.Uint32(os2[50:54])
+	ur4 := binary.BigEndian.Uint32(os2[54:58])
+	fsSelection := binary.BigEndian.Uint16(os2[62:64])
+	isItalic = fsSelection&0x0001 != 0
+	ranges = unicodeRangesFromOS2(ur1, ur2, ur3, ur4)
+	return weight, width, isItalic, ranges, nil
+}
+
+// parsePostBytes reads post's italicAngle (a 16.16 fixed-point field, in
+// degrees) and isFixedPitch (true for monospace fonts).
+func parsePostBytes(post []byte) (italicAngle int, isMonospace bool, err error) {
+	if len(post) < 16 {
+		return 0, false, fmt.Errorf("post table too short")
+	}
+	italicAngle = int(int32(binary.BigEndian.Uint32(post[4:8])) / 65536)
+	isMonospace = binary.BigEndian.Uint32(post[12:16]) != 0
+	return italicAngle, isMonospace, nil
+}
+
+// enrichFromTables fills in the OS/2- and post-derived fields sfnt's own API
+// doesn't expose (Weight, Width, IsItalic, IsMonospace, UnicodeRanges), and
+// merges in every name table record so localized names survive even when
+// metadata was originally built via sfnt.Parse/getFontMetadata. data must be
+// a full sfnt file (or a standalone face rebuilt by extractFace). Callers
+// run this as a best-effort enrichment pass: if a table is missing or
+// malformed, the corresponding fields are simply left at their zero value.
+func enrichFromTables(data []byte, metadata *FontMetadata, logWriter io.Writer) {
+	if nameTable, err := findTableBytes(data, "name"); err == nil {
+		if extra, err := parseNameTableBytes(nameTable, logWriter); err == nil {
+			if metadata.Names == nil {
+				metadata.Names = extra.Names
+			} else {
+				for id, byLang := range extra.Names {
+					if metadata.Names[id] == nil {
+						metadata.Names[id] = make(map[LanguageTag]string)
+					}
+					for tag, name := range byLang {
+						metadata.Names[id][tag] = name
+					}
+				}
+			}
+			if metadata.PostScriptName == "" {
+				metadata.PostScriptName = extra.PostScriptName
+			}
+			if metadata.Version == "" {
+				metadata.Version = extra.Version
+			}
+			if metadata.Designer == "" {
+				metadata.Designer = extra.Designer
+			}
+			if metadata.DesignerURL == "" {
+				metadata.DesignerURL = extra.DesignerURL
+			}
+			if metadata.Manufacturer == "" {
+				metadata.Manufacturer = extra.Manufacturer
+			}
+			if metadata.License == "" {
+				metadata.License = extra.License
+			}
+			if metadata.LicenseURL == "" {
+				metadata.LicenseURL = extra.LicenseURL
+			}
+			if metadata.Copyright == "" {
+				metadata.Copyright = extra.Copyright
+			}
+			if metadata.Trademark == "" {
+				metadata.Trademark = extra.Trademark
+			}
+		}
+	}
+
+	if os2, err := findTableBytes(data, "OS/2"); err == nil {
+		if weight, width, isItalic, ranges, err := parseOS2Bytes(os2); err == nil {
+			metadata.Weight = weight
+			metadata.Width = width
+			metadata.IsItalic = isItalic
+			metadata.UnicodeRanges = ranges
+		}
+	}
+
+	if post, err := findTableBytes(data, "post"); err == nil {
+		if italicAngle, isMonospace, err := parsePostBytes(post); err == nil {
+			metadata.ItalicAngle = italicAngle
+			metadata.IsMonospace = isMonospace
+		}
+	}
+}
+
 func getFontMetadata(f *sfnt.Font) (FontMetadata, error) {
 	var metadata FontMetadata
 	for _, nameID := range []sfnt.NameID{sfnt.NameIDFamily, sfnt.NameIDSubfamily, sfnt.NameIDFull, 16, 17} {
@@ -396,16 +1308,16 @@ func sanitizeFileName(name string) string {
 	return name
 }
 
-func resolveDuplicate(path string, logWriter io.Writer) (string, bool, error) {
+func resolveDuplicate(fs afero.Fs, path string, logWriter io.Writer) (string, bool, error) {
 	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
 	dir := filepath.Dir(path)
 	ext := filepath.Ext(path)
-	if _, err := os.Stat(path); err == nil {
+	if _, err := fs.Stat(path); err == nil {
 		fmt.Fprintf(logWriter, "La fuente '%s' ya existe\n", filepath.Base(path))
 		return "", true, nil // Retorna true para indicar que es un duplicado
 	}
 	for i := 1; ; i++ {
-		if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := fs.Stat(path); os.IsNotExist(err) {
 			return path, false, nil
 		}
 		path = filepath.Join(dir, fmt.Sprintf("%s %d%s", base, i, ext))
@@ -415,13 +1327,13 @@ func resolveDuplicate(path string, logWriter io.Writer) (string, bool, error) {
 	}
 }
 
-func copyFile(src, dst string) error {
-	in, err := os.Open(src)
+func copyFile(readFS, writeFS afero.Fs, src, dst string) error {
+	in, err := readFS.Open(src)
 	if err != nil {
 		return err
 	}
 	defer in.Close()
-	out, err := os.Create(dst)
+	out, err := writeFS.Create(dst)
 	if err != nil {
 		return err
 	}
@@ -429,9 +1341,9 @@ func copyFile(src, dst string) error {
 	if _, err := io.Copy(out, in); err != nil {
 		return err
 	}
-	info, err := os.Stat(src)
+	info, err := readFS.Stat(src)
 	if err != nil {
 		return err
 	}
-	return os.Chmod(dst, info.Mode())
+	return writeFS.Chmod(dst, info.Mode())
 }
@@ -0,0 +1,382 @@
+package fontprocessor
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/spf13/afero"
+)
+
+// nameRecordFixture is one record to bake into a hand-built `name` table via
+// buildNameTable.
+type nameRecordFixture struct {
+	platformID, encodingID, languageID uint16
+	nameID                             NameID
+	value                              string
+}
+
+// buildNameTable assembles a minimal OpenType `name` table (format 0, no
+// language-tag records) from records, encoding each value the same way a
+// real font would for its platform/encoding: UTF-16BE for Unicode/Windows,
+// raw bytes for Macintosh Roman.
+func buildNameTable(records []nameRecordFixture) []byte {
+	var storage bytes.Buffer
+	offsets := make([]uint16, len(records))
+	lengths := make([]uint16, len(records))
+	for i, r := range records {
+		var data []byte
+		if r.platformID == 1 && r.encodingID == 0 {
+			data = []byte(r.value)
+		} else {
+			for _, c := range r.value {
+				var buf [2]byte
+				binary.BigEndian.PutUint16(buf[:], uint16(c))
+				data = append(data, buf[:]...)
+			}
+		}
+		offsets[i] = uint16(storage.Len())
+		lengths[i] = uint16(len(data))
+		storage.Write(data)
+	}
+
+	var table bytes.Buffer
+	binary.Write(&table, binary.BigEndian, uint16(0))                 // version
+	binary.Write(&table, binary.BigEndian, uint16(len(records)))      // count
+	binary.Write(&table, binary.BigEndian, uint16(6+12*len(records))) // stringOffset
+	for i, r := range records {
+		binary.Write(&table, binary.BigEndian, r.platformID)
+		binary.Write(&table, binary.BigEndian, r.encodingID)
+		binary.Write(&table, binary.BigEndian, r.languageID)
+		binary.Write(&table, binary.BigEndian, uint16(r.nameID))
+		binary.Write(&table, binary.BigEndian, lengths[i])
+		binary.Write(&table, binary.BigEndian, offsets[i])
+	}
+	table.Write(storage.Bytes())
+	return table.Bytes()
+}
+
+// writeUintBase128 encodes v as a WOFF2 UIntBase128 value, the inverse of
+// readUintBase128.
+func writeUintBase128(v uint32) []byte {
+	if v == 0 {
+		return []byte{0}
+	}
+	var out []byte
+	for v > 0 {
+		out = append([]byte{byte(v & 0x7F)}, out...)
+		v >>= 7
+	}
+	for i := 0; i < len(out)-1; i++ {
+		out[i] |= 0x80
+	}
+	return out
+}
+
+func TestParseNameTableBytesLocalizedVariants(t *testing.T) {
+	const (
+		windowsUSEnglish = 0x0409
+		windowsJapanese  = 0x0411
+	)
+	nameTable := buildNameTable([]nameRecordFixture{
+		{3, 1, windowsUSEnglish, NameIDFamily, "Test Family"},
+		{3, 1, windowsJapanese, NameIDFamily, "テストファミリー"},
+		{3, 1, windowsUSEnglish, NameIDSubfamily, "Regular"},
+		{3, 1, windowsUSEnglish, NameIDPostScript, "TestFamily-Regular"},
+		{3, 1, windowsUSEnglish, NameIDDesigner, "Jane Designer"},
+	})
+
+	metadata, err := parseNameTableBytes(nameTable, io.Discard)
+	if err != nil {
+		t.Fatalf("parseNameTableBytes: %v", err)
+	}
+
+	if metadata.FamilyName != "Test Family" {
+		t.Errorf("FamilyName = %q, want %q", metadata.FamilyName, "Test Family")
+	}
+	if metadata.PostScriptName != "TestFamily-Regular" {
+		t.Errorf("PostScriptName = %q, want %q", metadata.PostScriptName, "TestFamily-Regular")
+	}
+	if metadata.Designer != "Jane Designer" {
+		t.Errorf("Designer = %q, want %q", metadata.Designer, "Jane Designer")
+	}
+
+	jaTag := LanguageTag{PlatformID: 3, EncodingID: 1, LanguageID: windowsJapanese}
+	if got := metadata.Names[NameIDFamily][jaTag]; got != "テストファミリー" {
+		t.Errorf("Names[NameIDFamily][ja] = %q, want the Japanese variant, preserved alongside FamilyName", got)
+	}
+}
+
+func TestParsePostBytes(t *testing.T) {
+	post := make([]byte, 16)
+	wantAngle := int32(-12 * 65536)
+	binary.BigEndian.PutUint32(post[4:8], uint32(wantAngle)) // italicAngle: -12 degrees
+	binary.BigEndian.PutUint32(post[12:16], 1)               // isFixedPitch
+
+	italicAngle, isMonospace, err := parsePostBytes(post)
+	if err != nil {
+		t.Fatalf("parsePostBytes: %v", err)
+	}
+	if italicAngle != -12 {
+		t.Errorf("italicAngle = %d, want -12", italicAngle)
+	}
+	if !isMonospace {
+		t.Errorf("isMonospace = false, want true")
+	}
+}
+
+func TestParseWOFFRoundTrip(t *testing.T) {
+	nameTable := buildNameTable([]nameRecordFixture{
+		{3, 1, 0x0409, NameIDFamily, "Woffy"},
+		{3, 1, 0x0409, NameIDFull, "Woffy Regular"},
+	})
+
+	const headerSize = 44
+	const entrySize = 20
+	tableOffset := headerSize + entrySize
+
+	header := make([]byte, headerSize)
+	copy(header[0:4], "wOFF")
+	binary.BigEndian.PutUint16(header[12:14], 1) // numTables
+
+	entry := make([]byte, entrySize)
+	copy(entry[0:4], "name")
+	binary.BigEndian.PutUint32(entry[4:8], uint32(tableOffset))
+	binary.BigEndian.PutUint32(entry[8:12], uint32(len(nameTable)))  // compLength
+	binary.BigEndian.PutUint32(entry[12:16], uint32(len(nameTable))) // origLength == compLength: stored raw
+
+	data := append(append(header, entry...), nameTable...)
+
+	metadata, err := parseWOFF(data, io.Discard)
+	if err != nil {
+		t.Fatalf("parseWOFF: %v", err)
+	}
+	if metadata.FamilyName != "Woffy" || metadata.FullName != "Woffy Regular" {
+		t.Errorf("metadata = %+v, want FamilyName=Woffy FullName=\"Woffy Regular\"", metadata)
+	}
+}
+
+func TestParseWOFF2RoundTrip(t *testing.T) {
+	nameTable := buildNameTable([]nameRecordFixture{
+		{3, 1, 0x0409, NameIDFamily, "Woffy2"},
+	})
+
+	var compressed bytes.Buffer
+	bw := brotli.NewWriter(&compressed)
+	if _, err := bw.Write(nameTable); err != nil {
+		t.Fatalf("brotli write: %v", err)
+	}
+	if err := bw.Close(); err != nil {
+		t.Fatalf("brotli close: %v", err)
+	}
+
+	const headerSize = 48
+	header := make([]byte, headerSize)
+	copy(header[0:4], "wOF2")
+	binary.BigEndian.PutUint16(header[12:14], 1) // numTables
+	binary.BigEndian.PutUint32(header[20:24], uint32(compressed.Len()))
+
+	// "name" is index 5 in woff2KnownTags; top two bits (transformVersion) left 0.
+	dirEntry := append([]byte{5}, writeUintBase128(uint32(len(nameTable)))...)
+
+	data := append(append(header, dirEntry...), compressed.Bytes()...)
+
+	metadata, err := parseWOFF2(data, io.Discard)
+	if err != nil {
+		t.Fatalf("parseWOFF2: %v", err)
+	}
+	if metadata.FamilyName != "Woffy2" {
+		t.Errorf("FamilyName = %q, want %q", metadata.FamilyName, "Woffy2")
+	}
+}
+
+// TestParseWOFF2TransformedGlyfBeforeName reproduces a real-world WOFF2
+// container: a transformed `glyf` table (sorted first, per the spec's
+// tag-ordering requirement) occupies far fewer bytes in the shared stream
+// (transformLength) than its reconstructed size (origLength). parseWOFF2
+// must advance by transformLength, not origLength, or the `name` table
+// that follows it gets sliced from the wrong offset.
+func TestParseWOFF2TransformedGlyfBeforeName(t *testing.T) {
+	nameTable := buildNameTable([]nameRecordFixture{
+		{3, 1, 0x0409, NameIDFamily, "Transformed"},
+	})
+	glyfStreamBytes := bytes.Repeat([]byte{0xAB}, 37) // transformed glyf data, far shorter than origLength
+
+	var compressed bytes.Buffer
+	bw := brotli.NewWriter(&compressed)
+	if _, err := bw.Write(append(append([]byte{}, glyfStreamBytes...), nameTable...)); err != nil {
+		t.Fatalf("brotli write: %v", err)
+	}
+	if err := bw.Close(); err != nil {
+		t.Fatalf("brotli close: %v", err)
+	}
+
+	const headerSize = 48
+	header := make([]byte, headerSize)
+	copy(header[0:4], "wOF2")
+	binary.BigEndian.PutUint16(header[12:14], 2) // numTables
+	binary.BigEndian.PutUint32(header[20:24], uint32(compressed.Len()))
+
+	// glyf is index 10 in woff2KnownTags; transformVersion 0 (top two bits)
+	// means transformed, so a transformLength field follows origLength.
+	glyfEntry := append([]byte{10}, writeUintBase128(5000)...)
+	glyfEntry = append(glyfEntry, writeUintBase128(uint32(len(glyfStreamBytes)))...)
+	// name is index 5; never transformed.
+	nameEntry := append([]byte{5}, writeUintBase128(uint32(len(nameTable)))...)
+
+	data := append(header, glyfEntry...)
+	data = append(data, nameEntry...)
+	data = append(data, compressed.Bytes()...)
+
+	metadata, err := parseWOFF2(data, io.Discard)
+	if err != nil {
+		t.Fatalf("parseWOFF2: %v", err)
+	}
+	if metadata.FamilyName != "Transformed" {
+		t.Errorf("FamilyName = %q, want %q", metadata.FamilyName, "Transformed")
+	}
+}
+
+func TestFaceExt(t *testing.T) {
+	data := append([]byte("OTTO"), []byte("\x00\x01\x00\x00")...)
+	offsets := []uint32{0, 4}
+
+	if got := faceExt(data, offsets, 0); got != ".otf" {
+		t.Errorf("faceExt(OTTO) = %q, want .otf", got)
+	}
+	if got := faceExt(data, offsets, 1); got != ".ttf" {
+		t.Errorf("faceExt(TrueType) = %q, want .ttf", got)
+	}
+}
+
+// TestExtractFaceFixesChecksumAdjustment builds a one-table (head-only) fake
+// sfnt face and checks that extractFace recomputes checksumAdjustment so the
+// rebuilt file satisfies the sfnt invariant: the sum of every big-endian
+// uint32 word in the font, taken mod 2^32, equals the magic constant
+// 0xB1B0AFBA. A stale checksumAdjustment copied from the source collection
+// would fail this check.
+func TestExtractFaceFixesChecksumAdjustment(t *testing.T) {
+	const dirSize = 12 + 16 // header + one table entry
+	head := make([]byte, 16)
+	binary.BigEndian.PutUint32(head[8:12], 0xDEADBEEF) // stale checksumAdjustment
+
+	data := make([]byte, dirSize+len(head))
+	copy(data[0:4], "\x00\x01\x00\x00")
+	binary.BigEndian.PutUint16(data[4:6], 1) // numTables
+	entry := data[12:28]
+	copy(entry[0:4], "head")
+	binary.BigEndian.PutUint32(entry[8:12], uint32(dirSize)) // srcOff
+	binary.BigEndian.PutUint32(entry[12:16], uint32(len(head)))
+	copy(data[dirSize:], head)
+
+	out := extractFace(data, []uint32{0}, 0)
+	if out == nil {
+		t.Fatalf("extractFace returned nil")
+	}
+	if len(out)%4 != 0 {
+		t.Fatalf("extractFace output length %d not 4-byte aligned", len(out))
+	}
+
+	var sum uint32
+	for i := 0; i < len(out); i += 4 {
+		sum += binary.BigEndian.Uint32(out[i : i+4])
+	}
+	if sum != 0xB1B0AFBA {
+		t.Errorf("whole-font checksum = %#x, want the sfnt magic constant %#x", sum, uint32(0xB1B0AFBA))
+	}
+}
+
+func TestResolveDuplicate(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	path, isDuplicate, err := resolveDuplicate(fs, "Roboto/Roboto.ttf", io.Discard)
+	if err != nil {
+		t.Fatalf("resolveDuplicate on a fresh path: %v", err)
+	}
+	if isDuplicate || path != "Roboto/Roboto.ttf" {
+		t.Fatalf("resolveDuplicate on a fresh path = (%q, %v), want (%q, false)", path, isDuplicate, "Roboto/Roboto.ttf")
+	}
+
+	if err := afero.WriteFile(fs, "Roboto/Roboto.ttf", []byte("data"), 0644); err != nil {
+		t.Fatalf("seed write: %v", err)
+	}
+
+	_, isDuplicate, err = resolveDuplicate(fs, "Roboto/Roboto.ttf", io.Discard)
+	if err != nil {
+		t.Fatalf("resolveDuplicate on an existing path: %v", err)
+	}
+	if !isDuplicate {
+		t.Errorf("resolveDuplicate on an existing path should report isDuplicate=true")
+	}
+}
+
+func TestIndexDedup(t *testing.T) {
+	idx := NewIndex(t.TempDir())
+
+	entry := IndexEntry{Hash: "abc123", InputPath: "raw_fonts/Roboto-Regular.ttf", Size: 42}
+	if _, ok := idx.LookupHash(entry.Hash); ok {
+		t.Fatalf("LookupHash found an entry before any were added")
+	}
+
+	idx.Add(entry)
+
+	got, ok := idx.LookupHash("abc123")
+	if !ok || got.InputPath != entry.InputPath {
+		t.Errorf("LookupHash(%q) = %+v, %v, want the added entry", entry.Hash, got, ok)
+	}
+
+	if _, ok := idx.LookupUnchanged(entry.InputPath, entry.Size, entry.ModTime); !ok {
+		t.Errorf("LookupUnchanged should report a match for the same path/size/modTime")
+	}
+	if _, ok := idx.LookupUnchanged(entry.InputPath, entry.Size+1, entry.ModTime); ok {
+		t.Errorf("LookupUnchanged should not match when size differs")
+	}
+}
+
+func TestZipOutputFsGroupsByFamily(t *testing.T) {
+	dir := t.TempDir()
+	z := NewZipOutputFs(dir)
+
+	write := func(outputSubPath, content string) {
+		f, err := z.Create(outputSubPath)
+		if err != nil {
+			t.Fatalf("Create(%q): %v", outputSubPath, err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatalf("Write(%q): %v", outputSubPath, err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatalf("Close(%q): %v", outputSubPath, err)
+		}
+	}
+
+	// Same outputDir-rooted paths processFace builds: filepath.Join(outputDir, family, fileName).
+	write(dir+"/Roboto/Roboto-Regular.ttf", "roboto bytes")
+	write(dir+"/OpenSans/OpenSans-Regular.ttf", "opensans bytes")
+
+	if err := z.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	for family, wantEntry := range map[string]string{
+		"Roboto":   "Roboto-Regular.ttf",
+		"OpenSans": "OpenSans-Regular.ttf",
+	} {
+		zr, err := zip.OpenReader(filepath.Join(dir, family+".zip"))
+		if err != nil {
+			t.Fatalf("opening %s.zip: %v", family, err)
+		}
+		if len(zr.File) != 1 || zr.File[0].Name != wantEntry {
+			names := make([]string, len(zr.File))
+			for i, f := range zr.File {
+				names[i] = f.Name
+			}
+			t.Errorf("%s.zip contains %v, want exactly [%q]", family, names, wantEntry)
+		}
+		zr.Close()
+	}
+}
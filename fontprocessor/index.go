@@ -0,0 +1,139 @@
+package fontprocessor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// IndexEntry records everything the Index needs to recognize a previously
+// processed font without re-reading or re-parsing it: its content hash for
+// true dedup, its path/size/mtime for a cheap "unchanged" check, and the
+// metadata ProcessFontFile extracted from it.
+type IndexEntry struct {
+	Hash          string
+	Size          int64
+	ModTime       time.Time
+	InputPath     string
+	OutputPath    string
+	FamilyName    string
+	SubfamilyName string
+	FullName      string
+	FontType      string
+	FaceIndex     int
+}
+
+// Index is a persistent, content-hash-keyed database of processed fonts,
+// stored as JSON in outputDir. It lets ProcessFontFile skip both re-parsing
+// unchanged inputs and re-copying fonts whose bytes are already present
+// under a different name, turning repeat runs over the same corpus into an
+// incremental scan instead of a full copy.
+type Index struct {
+	mu      sync.Mutex
+	path    string
+	ByHash  map[string]IndexEntry `json:"byHash"`
+	ByInput map[string]IndexEntry `json:"byInput"`
+}
+
+const indexFileName = "font_index.json"
+
+// NewIndex creates an empty Index backed by outputDir's index file,
+// discarding any existing one. Used for --rebuild.
+func NewIndex(outputDir string) *Index {
+	return &Index{
+		path:    filepath.Join(outputDir, indexFileName),
+		ByHash:  make(map[string]IndexEntry),
+		ByInput: make(map[string]IndexEntry),
+	}
+}
+
+// LoadIndex reads the index file from outputDir, returning an empty Index
+// if one doesn't exist yet.
+func LoadIndex(outputDir string) (*Index, error) {
+	idx := NewIndex(outputDir)
+	data, err := os.ReadFile(idx.path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index %s: %v", idx.path, err)
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("failed to parse index %s: %v", idx.path, err)
+	}
+	return idx, nil
+}
+
+// Save writes the index back to its JSON file in outputDir.
+func (idx *Index) Save() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal index: %v", err)
+	}
+	if err := os.WriteFile(idx.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write index %s: %v", idx.path, err)
+	}
+	return nil
+}
+
+// LookupHash reports whether a font with this content hash has already been
+// processed, regardless of its input path or filename.
+func (idx *Index) LookupHash(hash string) (IndexEntry, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	entry, ok := idx.ByHash[hash]
+	return entry, ok
+}
+
+// LookupUnchanged reports whether inputPath is already indexed with a
+// matching size and mtime, meaning it can be skipped without reparsing.
+func (idx *Index) LookupUnchanged(inputPath string, size int64, modTime time.Time) (IndexEntry, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	entry, ok := idx.ByInput[inputPath]
+	if !ok || entry.Size != size || !entry.ModTime.Equal(modTime) {
+		return IndexEntry{}, false
+	}
+	return entry, true
+}
+
+// Add records a processed font in the index.
+func (idx *Index) Add(entry IndexEntry) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.ByHash[entry.Hash] = entry
+	idx.ByInput[entry.InputPath] = entry
+}
+
+// Prune drops entries whose output files no longer exist, e.g. because the
+// user deleted them from outputDir between runs. It returns the number of
+// entries removed.
+func (idx *Index) Prune() (int, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	removed := 0
+	for hash, entry := range idx.ByHash {
+		if _, err := os.Stat(entry.OutputPath); os.IsNotExist(err) {
+			delete(idx.ByHash, hash)
+			delete(idx.ByInput, entry.InputPath)
+			removed++
+		} else if err != nil {
+			return removed, fmt.Errorf("failed to stat %s: %v", entry.OutputPath, err)
+		}
+	}
+	return removed, nil
+}
+
+// hashBytes computes the SHA-256 content hash used to key the index.
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
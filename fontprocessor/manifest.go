@@ -0,0 +1,50 @@
+package fontprocessor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+)
+
+const manifestFileName = "manifest.json"
+
+// ManifestEntry is one face's record in a family directory's manifest.json:
+// its full FontMetadata plus the path it was written to.
+type ManifestEntry struct {
+	FontMetadata
+	OutputPath string
+}
+
+// appendManifestEntry adds entry to <dir>/manifest.json, creating the file
+// if it doesn't exist yet. Callers must already hold dir's lock (the same
+// one guarding resolveDuplicate) since this is a read-modify-write.
+//
+// On a write-only backend like ZipOutputFs, the manifest can't be read back
+// mid-run, so each call starts from an empty list and adds one more
+// manifest.json entry to the family's archive, holding only the face written
+// by that call.
+func appendManifestEntry(fs afero.Fs, dir string, entry ManifestEntry) error {
+	manifestPath := filepath.Join(dir, manifestFileName)
+
+	var entries []ManifestEntry
+	if data, err := afero.ReadFile(fs, manifestPath); err == nil {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return fmt.Errorf("failed to parse existing manifest %s: %v", manifestPath, err)
+		}
+	} else if !os.IsNotExist(err) && err != ErrZipFsNotSupported {
+		return fmt.Errorf("failed to read manifest %s: %v", manifestPath, err)
+	}
+
+	entries = append(entries, entry)
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %v", err)
+	}
+	if err := afero.WriteFile(fs, manifestPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest %s: %v", manifestPath, err)
+	}
+	return nil
+}
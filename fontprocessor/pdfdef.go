@@ -0,0 +1,199 @@
+package fontprocessor
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// pdfFontDesc mirrors gofpdf's makefont FontDescriptor block.
+type pdfFontDesc struct {
+	Ascent       int
+	Descent      int
+	CapHeight    int
+	Flags        int
+	FontBBox     string
+	ItalicAngle  int
+	StemV        int
+	MissingWidth int
+}
+
+// pdfFontDef mirrors the JSON font definition gofpdf's makefont utility
+// produces, so fonts processed here can be fed straight into gofpdf's
+// AddFont without a separate makefont pass.
+type pdfFontDef struct {
+	Tp           string
+	Name         string
+	Desc         pdfFontDesc
+	Up           int
+	Ut           int
+	Cw           map[string]int
+	Enc          string
+	Diff         string
+	File         string
+	OriginalSize int
+}
+
+const (
+	flagFixedPitch  = 1 << 0
+	flagSerif       = 1 << 1
+	flagItalic      = 1 << 6
+	flagNonsymbolic = 1 << 5
+)
+
+// writePDFDef writes a gofpdf-compatible <FullName>.json font definition
+// next to outputPath (same directory, same base name, ".json" extension).
+func writePDFDef(writeFS afero.Fs, metadata *FontMetadata, outputPath, encoding string, originalSize int) error {
+	if encoding == "" {
+		encoding = "cp1252"
+	}
+	if encoding != "cp1252" {
+		return fmt.Errorf("unsupported PDF encoding %q", encoding)
+	}
+
+	f := metadata.font
+	unitsPerEm := f.UnitsPerEm()
+	scale := func(v int16) int {
+		return int(v) * 1000 / int(unitsPerEm)
+	}
+
+	var buf sfnt.Buffer
+	fontMetrics, err := f.Metrics(&buf, fixed.I(int(unitsPerEm)), font.HintingNone)
+	if err != nil {
+		return fmt.Errorf("failed to read font metrics: %v", err)
+	}
+
+	ascent := scale(int16(fontMetrics.Ascent.Round()))
+	descent := scale(int16(fontMetrics.Descent.Round()))
+	// sfnt doesn't expose OS/2's sCapHeight, so fall back to ascent, the
+	// same approximation gofpdf's own makefont uses for fonts whose OS/2
+	// table is missing or too old to carry it.
+	capHeight := ascent
+
+	flags := flagNonsymbolic
+	isItalic := strings.Contains(strings.ToLower(metadata.SubfamilyName), "italic") ||
+		strings.Contains(strings.ToLower(metadata.SubfamilyName), "oblique")
+	if isItalic {
+		flags |= flagItalic
+	}
+	if isFixedPitch(f, &buf) {
+		flags |= flagFixedPitch
+	}
+
+	cw, err := glyphWidths(f, &buf, scale)
+	if err != nil {
+		return fmt.Errorf("failed to compute glyph widths: %v", err)
+	}
+
+	def := pdfFontDef{
+		Tp:   "TrueType",
+		Name: sanitizeFileName(metadata.FullName),
+		Desc: pdfFontDesc{
+			Ascent:       ascent,
+			Descent:      -descent,
+			CapHeight:    capHeight,
+			Flags:        flags,
+			FontBBox:     "[0 0 1000 1000]",
+			ItalicAngle:  metadata.ItalicAngle,
+			StemV:        70,
+			MissingWidth: cw["32"],
+		},
+		Up:           -100,
+		Ut:           50,
+		Cw:           cw,
+		Enc:          "cp1252",
+		Diff:         "",
+		File:         filepath.Base(outputPath),
+		OriginalSize: originalSize,
+	}
+
+	data, err := json.MarshalIndent(def, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal font definition: %v", err)
+	}
+
+	defPath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".json"
+	if err := afero.WriteFile(writeFS, defPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", defPath, err)
+	}
+	return nil
+}
+
+// glyphWidths computes the Cw advance-width table gofpdf expects: one entry
+// per cp1252 code point 0-255, keyed by its decimal string representation
+// (matching makefont's own JSON output). Code points the font has no glyph
+// for, and the unassigned cp1252 bytes, get the width of glyph 0 (.notdef).
+func glyphWidths(f *sfnt.Font, buf *sfnt.Buffer, scale func(int16) int) (map[string]int, error) {
+	cw := make(map[string]int, 256)
+	for code := 0; code < 256; code++ {
+		var idx sfnt.GlyphIndex
+		if r := cp1252ToRune(byte(code)); r != 0 {
+			if i, err := f.GlyphIndex(buf, r); err == nil {
+				idx = i
+			}
+		}
+		advance, err := f.GlyphAdvance(buf, idx, fixed.I(int(f.UnitsPerEm())), font.HintingNone)
+		if err != nil {
+			continue
+		}
+		cw[fmt.Sprintf("%d", code)] = scale(int16(advance.Round()))
+	}
+	return cw, nil
+}
+
+// isFixedPitch reports whether every mapped glyph for the ASCII characters
+// 'A' (0x41) through '~' (0x7e) shares the same advance width, i.e. the
+// font is monospace.
+func isFixedPitch(f *sfnt.Font, buf *sfnt.Buffer) bool {
+	var first fixed.Int26_6
+	seen := false
+	for code := 0x41; code < 0x7f; code++ {
+		idx, err := f.GlyphIndex(buf, rune(code))
+		if err != nil || idx == 0 {
+			continue
+		}
+		advance, err := f.GlyphAdvance(buf, idx, fixed.I(int(f.UnitsPerEm())), font.HintingNone)
+		if err != nil {
+			continue
+		}
+		if !seen {
+			first = advance
+			seen = true
+			continue
+		}
+		if advance != first {
+			return false
+		}
+	}
+	return seen
+}
+
+// cp1252ToRune maps a Windows-1252 code point to its Unicode rune, returning
+// 0 for the handful of unassigned bytes in the C1 control range.
+func cp1252ToRune(b byte) rune {
+	if r, ok := cp1252HighRunes[b]; ok {
+		return r
+	}
+	if b < 0x80 || b >= 0xa0 {
+		return rune(b)
+	}
+	return 0
+}
+
+// cp1252HighRunes holds the Windows-1252 code points in 0x80-0x9f that
+// diverge from Latin-1.
+var cp1252HighRunes = map[byte]rune{
+	0x80: 0x20ac, 0x82: 0x201a, 0x83: 0x0192, 0x84: 0x201e,
+	0x85: 0x2026, 0x86: 0x2020, 0x87: 0x2021, 0x88: 0x02c6,
+	0x89: 0x2030, 0x8a: 0x0160, 0x8b: 0x2039, 0x8c: 0x0152,
+	0x8e: 0x017d, 0x91: 0x2018, 0x92: 0x2019, 0x93: 0x201c,
+	0x94: 0x201d, 0x95: 0x2022, 0x96: 0x2013, 0x97: 0x2014,
+	0x98: 0x02dc, 0x99: 0x2122, 0x9a: 0x0161, 0x9b: 0x203a,
+	0x9c: 0x0153, 0x9e: 0x017e, 0x9f: 0x0178,
+}
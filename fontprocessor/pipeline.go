@@ -0,0 +1,161 @@
+package fontprocessor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/spf13/afero"
+)
+
+// Options configures ProcessDirectory's concurrent processing pipeline.
+type Options struct {
+	// Workers is the number of concurrent worker goroutines. Defaults to
+	// runtime.NumCPU() when zero or negative.
+	Workers int
+	// Context, if set, cancels an in-progress scan; a walk in progress
+	// stops pushing new files and workers drain what's already queued.
+	Context context.Context
+	// FileOptions configures every per-file read, write, and PDF-definition
+	// pass; it's passed through to ProcessFontFile unchanged for each file.
+	FileOptions
+	// OnProgress, if set, is called once per processed (or failed) file
+	// from a single goroutine, so it's safe to update shared state from it.
+	OnProgress func(path string, err error)
+}
+
+// dirLocker hands out a per-output-directory mutex so concurrent workers
+// writing into the same family directory serialize their
+// create-then-resolveDuplicate-then-write sequence instead of racing on it
+// and both finding the same output path free and writing to it.
+type dirLocker struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func newDirLocker() *dirLocker {
+	return &dirLocker{locks: make(map[string]*sync.Mutex)}
+}
+
+func (d *dirLocker) lock(dir string) func() {
+	d.mu.Lock()
+	l, ok := d.locks[dir]
+	if !ok {
+		l = &sync.Mutex{}
+		d.locks[dir] = l
+	}
+	d.mu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+// ProcessDirectory walks inputDir and processes every recognized font file
+// through a pool of opts.Workers goroutines, writing output under
+// outputDir. The walker pushes paths onto a buffered channel; workers
+// consume them and report results on a second channel drained by a single
+// logger goroutine, keeping logWriter writes serialized.
+func ProcessDirectory(inputDir, outputDir string, logWriter io.Writer, opts Options) error {
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	fileOpts := opts.FileOptions
+	if fileOpts.ReadFS == nil {
+		fileOpts.ReadFS = afero.NewOsFs()
+	}
+	if fileOpts.WriteFS == nil {
+		fileOpts.WriteFS = afero.NewOsFs()
+	}
+
+	type result struct {
+		path string
+		log  string
+		err  error
+	}
+
+	paths := make(chan string, workers*2)
+	results := make(chan result, workers*2)
+	locker := newDirLocker()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				// Each worker logs to its own buffer rather than logWriter
+				// directly, so the logger goroutine below can serialize
+				// every worker's output instead of interleaving writes.
+				var buf bytes.Buffer
+				err := processFontFile(inputDir, path, outputDir, &buf, fileOpts, locker)
+				results <- result{path: path, log: buf.String(), err: err}
+			}
+		}()
+	}
+
+	logDone := make(chan struct{})
+	go func() {
+		defer close(logDone)
+		for r := range results {
+			if r.log != "" {
+				io.WriteString(logWriter, r.log)
+			}
+			if r.err != nil {
+				fmt.Fprintf(logWriter, "Failed to process file %s: %v\n", r.path, r.err)
+			}
+			if opts.OnProgress != nil {
+				opts.OnProgress(r.path, r.err)
+			}
+		}
+	}()
+
+	walkErr := filepath.Walk(inputDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			fmt.Fprintf(logWriter, "Error accessing path %s: %v\n", path, err)
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if !IsRecognizedFontExt(path) {
+			return nil
+		}
+		select {
+		case paths <- path:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(paths)
+	wg.Wait()
+	close(results)
+	<-logDone
+
+	if walkErr != nil && walkErr != context.Canceled && walkErr != context.DeadlineExceeded {
+		return fmt.Errorf("error walking input directory: %v", walkErr)
+	}
+	return ctx.Err()
+}
+
+// IsRecognizedFontExt reports whether path has an extension ProcessDirectory
+// and ProcessFontFile know how to handle.
+func IsRecognizedFontExt(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case "", ".pfa", ".pfb", ".ttc", ".otc", ".woff", ".woff2":
+		return true
+	default:
+		return false
+	}
+}
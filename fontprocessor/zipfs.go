@@ -0,0 +1,194 @@
+package fontprocessor
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// ErrZipFsNotSupported is returned by ZipOutputFs operations that have no
+// meaningful equivalent when writing into an append-only zip archive
+// (reading back, renaming, or removing an entry already written).
+var ErrZipFsNotSupported = errors.New("operation not supported on zip output filesystem")
+
+// ZipOutputFs is an afero.Fs that groups every family (the first path
+// segment of any name it's given) into its own outputDir/<Family>.zip
+// archive using archive/zip with Deflate compression, instead of writing
+// loose files. It implements only the subset of afero.Fs that
+// ProcessFontFile's write path exercises — Stat reports whether this run
+// has already written a given entry, since a zip archive can't be read back
+// mid-write.
+type ZipOutputFs struct {
+	mu      sync.Mutex
+	baseDir string
+	writers map[string]*zip.Writer
+	handles map[string]*os.File
+	written map[string]bool
+}
+
+// NewZipOutputFs returns a ZipOutputFs that creates family archives under
+// baseDir (normally the tool's outputDir).
+func NewZipOutputFs(baseDir string) *ZipOutputFs {
+	return &ZipOutputFs{
+		baseDir: baseDir,
+		writers: make(map[string]*zip.Writer),
+		handles: make(map[string]*os.File),
+		written: make(map[string]bool),
+	}
+}
+
+func splitFamily(name string) (family, rest string) {
+	clean := path.Clean(filepath.ToSlash(name))
+	parts := strings.SplitN(clean, "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// relativeToBase strips z.baseDir off the front of name. Callers (same as
+// the real OS filesystem backend) pass paths rooted at outputDir, e.g.
+// "output_fonts/Roboto/Roboto Bold.ttf"; splitFamily needs the family to be
+// the first segment, i.e. "Roboto/Roboto Bold.ttf".
+func (z *ZipOutputFs) relativeToBase(name string) string {
+	clean := path.Clean(filepath.ToSlash(name))
+	base := path.Clean(filepath.ToSlash(z.baseDir))
+	if base == "." || base == "" {
+		return clean
+	}
+	if clean == base {
+		return ""
+	}
+	if rest := strings.TrimPrefix(clean, base+"/"); rest != clean {
+		return rest
+	}
+	return clean
+}
+
+func (z *ZipOutputFs) writerFor(family string) (*zip.Writer, error) {
+	if w, ok := z.writers[family]; ok {
+		return w, nil
+	}
+	f, err := os.Create(filepath.Join(z.baseDir, family+".zip"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive for family %q: %v", family, err)
+	}
+	w := zip.NewWriter(f)
+	z.handles[family] = f
+	z.writers[family] = w
+	return w, nil
+}
+
+// Create opens name for writing inside its family's archive. name is
+// rooted at z.baseDir (the same outputDir-prefixed paths the OS filesystem
+// backend receives); once that prefix is stripped, the first remaining path
+// segment names the family (e.g. "Roboto/Roboto Bold.ttf").
+func (z *ZipOutputFs) Create(name string) (afero.File, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	relName := z.relativeToBase(name)
+	family, rest := splitFamily(relName)
+	if rest == "" {
+		return nil, fmt.Errorf("zip output: %q has no family directory", name)
+	}
+	w, err := z.writerFor(family)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := w.Create(rest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zip entry %q: %v", name, err)
+	}
+	z.written[relName] = true
+	return &zipEntryFile{name: relName, entry: entry}, nil
+}
+
+func (z *ZipOutputFs) Mkdir(name string, perm os.FileMode) error    { return nil }
+func (z *ZipOutputFs) MkdirAll(path string, perm os.FileMode) error { return nil }
+
+func (z *ZipOutputFs) Open(name string) (afero.File, error) { return nil, ErrZipFsNotSupported }
+func (z *ZipOutputFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	return z.Create(name)
+}
+func (z *ZipOutputFs) Remove(name string) error                          { return ErrZipFsNotSupported }
+func (z *ZipOutputFs) RemoveAll(path string) error                       { return ErrZipFsNotSupported }
+func (z *ZipOutputFs) Rename(oldname, newname string) error              { return ErrZipFsNotSupported }
+func (z *ZipOutputFs) Name() string                                      { return "ZipOutputFs" }
+func (z *ZipOutputFs) Chmod(name string, mode os.FileMode) error         { return nil }
+func (z *ZipOutputFs) Chtimes(name string, atime, mtime time.Time) error { return nil }
+func (z *ZipOutputFs) Chown(name string, uid, gid int) error             { return nil }
+
+// Stat reports whether name has already been written during this run
+// (resolveDuplicate uses this to report an already-written entry as a
+// duplicate and skip it instead of writing it again).
+func (z *ZipOutputFs) Stat(name string) (os.FileInfo, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	relName := z.relativeToBase(name)
+	if z.written[relName] {
+		return zipFileInfo{name: path.Base(relName)}, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+// Close finalizes every family archive opened by this Fs. Call once all
+// processing through it is done.
+func (z *ZipOutputFs) Close() error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	var firstErr error
+	for family, w := range z.writers {
+		if err := w.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if err := z.handles[family].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// zipEntryFile adapts a zip.Writer entry (a write-only io.Writer with no
+// Seek/Stat support) to the afero.File interface.
+type zipEntryFile struct {
+	name  string
+	entry io.Writer
+}
+
+func (f *zipEntryFile) Write(p []byte) (int, error)       { return f.entry.Write(p) }
+func (f *zipEntryFile) WriteString(s string) (int, error) { return f.entry.Write([]byte(s)) }
+func (f *zipEntryFile) Close() error                      { return nil }
+func (f *zipEntryFile) Name() string                      { return f.name }
+func (f *zipEntryFile) Sync() error                       { return nil }
+
+func (f *zipEntryFile) Read(p []byte) (int, error)                   { return 0, ErrZipFsNotSupported }
+func (f *zipEntryFile) ReadAt(p []byte, off int64) (int, error)      { return 0, ErrZipFsNotSupported }
+func (f *zipEntryFile) Seek(offset int64, whence int) (int64, error) { return 0, ErrZipFsNotSupported }
+func (f *zipEntryFile) WriteAt(p []byte, off int64) (int, error)     { return 0, ErrZipFsNotSupported }
+func (f *zipEntryFile) Stat() (os.FileInfo, error)                   { return nil, ErrZipFsNotSupported }
+func (f *zipEntryFile) Truncate(size int64) error                    { return ErrZipFsNotSupported }
+func (f *zipEntryFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, ErrZipFsNotSupported
+}
+func (f *zipEntryFile) Readdirnames(n int) ([]string, error) { return nil, ErrZipFsNotSupported }
+
+// zipFileInfo is the minimal os.FileInfo ZipOutputFs.Stat needs to report
+// that an entry already exists.
+type zipFileInfo struct{ name string }
+
+func (fi zipFileInfo) Name() string       { return fi.name }
+func (fi zipFileInfo) Size() int64        { return 0 }
+func (fi zipFileInfo) Mode() os.FileMode  { return 0644 }
+func (fi zipFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi zipFileInfo) IsDir() bool        { return false }
+func (fi zipFileInfo) Sys() interface{}   { return nil }
@@ -2,16 +2,28 @@ package main
 
 import (
 	"Raw2Font/fontprocessor"
+	"context"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
+	"runtime"
 	"time"
+
+	"github.com/spf13/afero"
 )
 
 func main() {
+	rebuild := flag.Bool("rebuild", false, "ignore the existing font index and rescan every input file")
+	workers := flag.Int("workers", runtime.NumCPU(), "number of concurrent worker goroutines")
+	output := flag.String("output", "dir", "output backend: \"dir\" for loose files, \"zip\" to group each family into outputDir/<Family>.zip")
+	emitPDFDef := flag.Bool("emit-pdfdef", false, "also write a gofpdf-compatible <FullName>.json font definition next to every copied face")
+	pdfEncoding := flag.String("pdf-encoding", "cp1252", "single-byte encoding used to compute the Cw advance-width table in emitted font definitions (only cp1252 is supported)")
+	emitManifest := flag.Bool("emit-manifest", false, "also maintain a manifest.json in each output family directory enumerating every face's full metadata")
+	flag.Parse()
+
 	// Specify input and output directories
 	inputDir := "raw_fonts"     // Replace with your input directory path
 	outputDir := "output_fonts" // Replace with your output directory path
@@ -28,29 +40,64 @@ func main() {
 	}
 	defer logWriter.Close()
 
-	// Process font files
-	err = filepath.Walk(inputDir, func(path string, info os.FileInfo, err error) error {
+	// Select the output backend
+	var writeFS afero.Fs
+	var zipFS *fontprocessor.ZipOutputFs
+	switch *output {
+	case "dir":
+		writeFS = afero.NewOsFs()
+	case "zip":
+		zipFS = fontprocessor.NewZipOutputFs(outputDir)
+		writeFS = zipFS
+	default:
+		log.Fatalf("Unknown --output backend %q (want \"dir\" or \"zip\")", *output)
+	}
+
+	// Load the persistent font index, unless --rebuild was requested
+	var idx *fontprocessor.Index
+	if *rebuild {
+		idx = fontprocessor.NewIndex(outputDir)
+	} else {
+		idx, err = fontprocessor.LoadIndex(outputDir)
 		if err != nil {
-			log.Printf("Error accessing path %s: %v", path, err)
-			return nil
-		}
-		if info.IsDir() {
-			return nil
+			log.Fatalf("Failed to load font index: %v", err)
 		}
-		// Filter for no-extension files or Type 1 fonts
-		ext := strings.ToLower(filepath.Ext(path))
-		if ext != "" && ext != ".pfa" && ext != ".pfb" {
-			return nil
+		// Prune's os.Stat-based existence check only makes sense against the
+		// "dir" backend: with "zip", every indexed OutputPath lives inside a
+		// .zip that never exists as a loose file, so pruning there would
+		// delete every entry on every run and turn the index into a no-op.
+		if zipFS == nil {
+			if removed, err := idx.Prune(); err != nil {
+				log.Printf("Failed to prune font index: %v", err)
+			} else if removed > 0 {
+				log.Printf("Pruned %d stale entries from font index", removed)
+			}
 		}
-		// Process font file
-		if err := fontprocessor.ProcessFontFile(inputDir, path, outputDir, logWriter); err != nil {
-			log.Printf("Failed to process file %s: %v", path, err)
-			return nil
+	}
+
+	// Process font files through a concurrent worker pool
+	opts := fontprocessor.Options{
+		Workers: *workers,
+		Context: context.Background(),
+		FileOptions: fontprocessor.FileOptions{
+			Index:        idx,
+			WriteFS:      writeFS,
+			EmitPDFDef:   *emitPDFDef,
+			PDFEncoding:  *pdfEncoding,
+			EmitManifest: *emitManifest,
+		},
+	}
+	if err := fontprocessor.ProcessDirectory(inputDir, outputDir, logWriter, opts); err != nil {
+		log.Printf("Error processing input directory: %v", err)
+	}
+	if zipFS != nil {
+		if err := zipFS.Close(); err != nil {
+			log.Printf("Failed to finalize zip output: %v", err)
 		}
-		return nil
-	})
-	if err != nil {
-		log.Printf("Error walking input directory: %v", err)
+	}
+
+	if err := idx.Save(); err != nil {
+		log.Printf("Failed to save font index: %v", err)
 	}
 
 	// Print log file creation message as last terminal output